@@ -0,0 +1,224 @@
+package migrator
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// sqlFileRe matches migration filenames of the form NNNN_name.up.sql,
+// NNNN_name.down.sql and their .notx variants (NNNN_name.up.notx.sql,
+// NNNN_name.down.notx.sql) for migrations that must run outside a transaction.
+var sqlFileRe = regexp.MustCompile(`^([0-9]+)_(.+)\.(up|down)(\.notx)?\.sql$`)
+
+const (
+	statementBeginMarker = "-- +migrator StatementBegin"
+	statementEndMarker   = "-- +migrator StatementEnd"
+)
+
+// FromFS scans fsys for SQL migration files under dir named NNNN_name.up.sql and
+// NNNN_name.down.sql (or their .notx.sql variant, for migrations that must run outside
+// a transaction) and returns them as an ordered list of *Migration/*MigrationNoTx values
+// suitable for passing to Migrations. Pass a Go 1.16 embed.FS to ship migrations
+// embedded in the binary instead of hand-written Go closures.
+//
+// Each file is split into statements at every line ending in a semicolon, and the
+// statements are executed one at a time, so drivers that don't accept batched statements
+// work. A statement that itself contains such semicolons (e.g. a function or trigger
+// body) must be wrapped between a "-- +migrator StatementBegin" and
+// "-- +migrator StatementEnd" comment pair so it is executed as a single statement.
+func FromFS(fsys fs.FS, dir string) ([]interface{}, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("migrator: reading migrations dir: %w", err)
+	}
+
+	type fileSet struct {
+		version string
+		name    string
+		notx    bool
+		up      string
+		down    string
+	}
+	sets := make(map[string]*fileSet)
+	var order []string
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		matches := sqlFileRe.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+		version, name, direction, notx := matches[1], matches[2], matches[3], matches[4] != ""
+
+		key := version + "_" + name
+		if notx {
+			key += ".notx"
+		}
+		set, ok := sets[key]
+		if !ok {
+			set = &fileSet{version: version, name: name, notx: notx}
+			sets[key] = set
+			order = append(order, key)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("migrator: reading %s: %w", entry.Name(), err)
+		}
+		switch direction {
+		case "up":
+			set.up = string(content)
+		case "down":
+			set.down = string(content)
+		}
+	}
+
+	sort.Strings(order)
+
+	migrations := make([]interface{}, 0, len(order))
+	for _, key := range order {
+		set := sets[key]
+		if set.up == "" {
+			return nil, fmt.Errorf("migrator: migration %s_%s has no .up.sql file", set.version, set.name)
+		}
+
+		upStatements, err := splitStatements(set.up)
+		if err != nil {
+			return nil, fmt.Errorf("migrator: parsing %s_%s.up.sql: %w", set.version, set.name, err)
+		}
+		var downStatements []string
+		if set.down != "" {
+			downStatements, err = splitStatements(set.down)
+			if err != nil {
+				return nil, fmt.Errorf("migrator: parsing %s_%s.down.sql: %w", set.version, set.name, err)
+			}
+		}
+
+		// Only the up script is hashed: it's what actually ran and got recorded, so
+		// editing a down script (e.g. adding a rollback path after the fact) must not
+		// trip the checksum-drift check on an otherwise up-to-date database.
+		checksum := checksumOfContent(set.up)
+
+		if set.notx {
+			migrations = append(migrations, &MigrationNoTx{
+				Version:  set.version,
+				Name:     set.name,
+				Checksum: checksum,
+				Func:     execStatementsNoTx(upStatements),
+				Down:     execStatementsNoTxOrNil(downStatements),
+			})
+			continue
+		}
+		migrations = append(migrations, &Migration{
+			Version:  set.version,
+			Name:     set.name,
+			Checksum: checksum,
+			Func:     execStatements(upStatements),
+			Down:     execStatementsOrNil(downStatements),
+		})
+	}
+
+	return migrations, nil
+}
+
+// splitStatements splits sqlContent into individual statements on semicolons, except
+// inside a "-- +migrator StatementBegin"/"-- +migrator StatementEnd" block, which is
+// kept as a single statement regardless of the semicolons it contains.
+func splitStatements(sqlContent string) ([]string, error) {
+	var statements []string
+	var buf strings.Builder
+	inStatementBlock := false
+
+	scanner := bufio.NewScanner(strings.NewReader(sqlContent))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, statementBeginMarker):
+			inStatementBlock = true
+			continue
+		case strings.HasPrefix(trimmed, statementEndMarker):
+			if !inStatementBlock {
+				return nil, fmt.Errorf("migrator: %q found without a matching StatementBegin", statementEndMarker)
+			}
+			inStatementBlock = false
+			if stmt := strings.TrimSpace(buf.String()); stmt != "" {
+				statements = append(statements, stmt)
+			}
+			buf.Reset()
+			continue
+		}
+
+		buf.WriteString(line)
+		buf.WriteString("\n")
+		if !inStatementBlock && strings.HasSuffix(trimmed, ";") {
+			if stmt := strings.TrimSpace(buf.String()); stmt != "" {
+				statements = append(statements, stmt)
+			}
+			buf.Reset()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if inStatementBlock {
+		return nil, fmt.Errorf("migrator: %q without a matching StatementEnd", statementBeginMarker)
+	}
+	if stmt := strings.TrimSpace(buf.String()); stmt != "" {
+		statements = append(statements, stmt)
+	}
+
+	return statements, nil
+}
+
+func checksumOfContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func execStatements(statements []string) func(*sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, stmt := range statements {
+			if _, err := tx.Exec(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func execStatementsOrNil(statements []string) func(*sql.Tx) error {
+	if len(statements) == 0 {
+		return nil
+	}
+	return execStatements(statements)
+}
+
+func execStatementsNoTx(statements []string) func(*sql.DB) error {
+	return func(db *sql.DB) error {
+		for _, stmt := range statements {
+			if _, err := db.Exec(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func execStatementsNoTxOrNil(statements []string) func(*sql.DB) error {
+	if len(statements) == 0 {
+		return nil
+	}
+	return execStatementsNoTx(statements)
+}
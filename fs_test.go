@@ -0,0 +1,143 @@
+package migrator
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0001_create_foo.up.sql":   {Data: []byte("CREATE TABLE foo (id INT PRIMARY KEY);")},
+		"migrations/0001_create_foo.down.sql": {Data: []byte("DROP TABLE foo;")},
+		"migrations/0002_seed_foo.up.notx.sql": {Data: []byte(`
+-- +migrator StatementBegin
+CREATE OR REPLACE FUNCTION seed_foo() RETURNS void AS $$
+BEGIN
+	INSERT INTO foo (id) VALUES (1);
+END;
+$$ LANGUAGE plpgsql;
+-- +migrator StatementEnd
+SELECT seed_foo();
+`)},
+	}
+
+	migrations, err := FromFS(fsys, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("expected 2 migrations, got %d", len(migrations))
+	}
+
+	first, ok := migrations[0].(*Migration)
+	if !ok {
+		t.Fatalf("expected first migration to be *Migration, got %T", migrations[0])
+	}
+	if first.Version != "0001" || first.Name != "create_foo" {
+		t.Fatalf("unexpected version/name: %s/%s", first.Version, first.Name)
+	}
+	if first.Down == nil {
+		t.Fatal("expected first migration to have a Down function")
+	}
+	if first.Checksum == "" {
+		t.Fatal("expected first migration to have a checksum")
+	}
+
+	second, ok := migrations[1].(*MigrationNoTx)
+	if !ok {
+		t.Fatalf("expected second migration to be *MigrationNoTx, got %T", migrations[1])
+	}
+	if second.Version != "0002" || second.Name != "seed_foo" {
+		t.Fatalf("unexpected version/name: %s/%s", second.Version, second.Name)
+	}
+	if second.Down != nil {
+		t.Fatal("expected second migration to have no Down function")
+	}
+}
+
+func TestFromFSChecksumIgnoresDown(t *testing.T) {
+	withoutDown := fstest.MapFS{
+		"migrations/0001_create_foo.up.sql": {Data: []byte("CREATE TABLE foo (id INT PRIMARY KEY);")},
+	}
+	withDown := fstest.MapFS{
+		"migrations/0001_create_foo.up.sql":   {Data: []byte("CREATE TABLE foo (id INT PRIMARY KEY);")},
+		"migrations/0001_create_foo.down.sql": {Data: []byte("DROP TABLE foo;")},
+	}
+
+	before, err := FromFS(withoutDown, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := FromFS(withDown, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	beforeChecksum := before[0].(*Migration).Checksum
+	afterChecksum := after[0].(*Migration).Checksum
+	if beforeChecksum != afterChecksum {
+		t.Fatalf("adding a .down.sql file changed the checksum: %q != %q", beforeChecksum, afterChecksum)
+	}
+}
+
+func TestFromFSMissingUp(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0001_create_foo.down.sql": {Data: []byte("DROP TABLE foo;")},
+	}
+
+	if _, err := FromFS(fsys, "migrations"); err == nil {
+		t.Fatal("expected an error for a migration with no .up.sql file")
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{
+			name:  "simple statements",
+			input: "CREATE TABLE foo (id INT);\nINSERT INTO foo VALUES (1);\n",
+			want:  2,
+		},
+		{
+			name: "statement block kept whole",
+			input: `-- +migrator StatementBegin
+CREATE FUNCTION foo() RETURNS void AS $$ BEGIN INSERT INTO foo VALUES (1); END; $$ LANGUAGE plpgsql;
+-- +migrator StatementEnd
+SELECT foo();
+`,
+			want: 2,
+		},
+		{
+			name:    "unterminated statement block",
+			input:   "-- +migrator StatementBegin\nCREATE FUNCTION foo() RETURNS void AS $$ BEGIN END; $$ LANGUAGE plpgsql;\n",
+			wantErr: true,
+		},
+		{
+			name:    "statement end without begin",
+			input:   "-- +migrator StatementEnd\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statements, err := splitStatements(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(statements) != tt.want {
+				t.Fatalf("expected %d statements, got %d: %v", tt.want, len(statements), statements)
+			}
+		})
+	}
+}
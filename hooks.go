@@ -0,0 +1,150 @@
+package migrator
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// MigrationInfo describes a migration for Hooks callbacks.
+type MigrationInfo struct {
+	Version string
+	Name    string
+	Type    string // "tx" or "no-tx"
+}
+
+// Hooks lets callers observe migration execution without forking the library, e.g. to
+// emit Prometheus metrics, push structured events, run ANALYZE after DDL, or invalidate
+// caches.
+type Hooks interface {
+	// BeforeAll is called once before Migrate/Rollback starts executing the given
+	// migrations, which may be empty if there is nothing to do.
+	BeforeAll(ctx context.Context, migrations []MigrationInfo)
+	// AfterAll is called once after Migrate/Rollback has finished, with err set if the
+	// run failed partway through.
+	AfterAll(ctx context.Context, migrations []MigrationInfo, err error)
+	// BeforeMigration is called immediately before a single migration runs.
+	BeforeMigration(ctx context.Context, info MigrationInfo)
+	// AfterMigration is called immediately after a single migration runs, with err set
+	// if it failed and duration set to how long it took.
+	AfterMigration(ctx context.Context, info MigrationInfo, err error, duration time.Duration)
+}
+
+// HookFuncs is a Hooks implementation built from individual optional function fields,
+// letting callers implement only the callbacks they care about; nil fields are no-ops.
+type HookFuncs struct {
+	BeforeAllFunc       func(ctx context.Context, migrations []MigrationInfo)
+	AfterAllFunc        func(ctx context.Context, migrations []MigrationInfo, err error)
+	BeforeMigrationFunc func(ctx context.Context, info MigrationInfo)
+	AfterMigrationFunc  func(ctx context.Context, info MigrationInfo, err error, duration time.Duration)
+}
+
+// BeforeAll implements Hooks.
+func (h HookFuncs) BeforeAll(ctx context.Context, migrations []MigrationInfo) {
+	if h.BeforeAllFunc != nil {
+		h.BeforeAllFunc(ctx, migrations)
+	}
+}
+
+// AfterAll implements Hooks.
+func (h HookFuncs) AfterAll(ctx context.Context, migrations []MigrationInfo, err error) {
+	if h.AfterAllFunc != nil {
+		h.AfterAllFunc(ctx, migrations, err)
+	}
+}
+
+// BeforeMigration implements Hooks.
+func (h HookFuncs) BeforeMigration(ctx context.Context, info MigrationInfo) {
+	if h.BeforeMigrationFunc != nil {
+		h.BeforeMigrationFunc(ctx, info)
+	}
+}
+
+// AfterMigration implements Hooks.
+func (h HookFuncs) AfterMigration(ctx context.Context, info MigrationInfo, err error, duration time.Duration) {
+	if h.AfterMigrationFunc != nil {
+		h.AfterMigrationFunc(ctx, info, err, duration)
+	}
+}
+
+// WithHooks creates an option to observe migration execution via the given Hooks.
+func WithHooks(hooks Hooks) Option {
+	return func(m *Migrator) {
+		m.hooks = hooks
+	}
+}
+
+// WithMeter creates an option that records, on the given otel.Meter, a histogram of
+// per-migration duration and a counter of migration failures, complementing the
+// tracing spans Migrate and Rollback already emit. Metrics are opt-in: without this
+// option no instruments are created.
+func WithMeter(meter metric.Meter) Option {
+	return func(m *Migrator) {
+		m.meter = meter
+	}
+}
+
+// initMeter creates the duration histogram and failure counter on m.meter, if one was
+// configured via WithMeter.
+func (m *Migrator) initMeter() error {
+	if m.meter == nil {
+		return nil
+	}
+
+	duration, err := m.meter.Float64Histogram(
+		"migrator.migration.duration",
+		metric.WithDescription("Duration of individual migrations"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return err
+	}
+	m.durationHistogram = duration
+
+	failures, err := m.meter.Int64Counter(
+		"migrator.migration.failures",
+		metric.WithDescription("Number of migrations that failed to apply or roll back"),
+	)
+	if err != nil {
+		return err
+	}
+	m.failureCounter = failures
+
+	return nil
+}
+
+// instrument runs fn, calling m.hooks' Before/AfterMigration callbacks and recording
+// duration/failure metrics around it when configured.
+func (m *Migrator) instrument(ctx context.Context, info MigrationInfo, fn func() error) error {
+	if m.hooks != nil {
+		m.hooks.BeforeMigration(ctx, info)
+	}
+
+	start := time.Now()
+	err := fn()
+	duration := time.Since(start)
+
+	if m.hooks != nil {
+		m.hooks.AfterMigration(ctx, info, err, duration)
+	}
+
+	if m.durationHistogram != nil {
+		attrs := metric.WithAttributes(
+			attribute.String("version", info.Version),
+			attribute.String("name", info.Name),
+			attribute.String("type", info.Type),
+		)
+		m.durationHistogram.Record(ctx, duration.Seconds(), attrs)
+	}
+	if err != nil && m.failureCounter != nil {
+		m.failureCounter.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("version", info.Version),
+			attribute.String("name", info.Name),
+			attribute.String("type", info.Type),
+		))
+	}
+
+	return err
+}
@@ -0,0 +1,61 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInstrumentCallsHooks(t *testing.T) {
+	var beforeInfo, afterInfo MigrationInfo
+	var afterErr error
+	var afterDuration time.Duration
+	beforeCalled, afterCalled := false, false
+
+	m := &Migrator{
+		hooks: HookFuncs{
+			BeforeMigrationFunc: func(ctx context.Context, info MigrationInfo) {
+				beforeCalled = true
+				beforeInfo = info
+			},
+			AfterMigrationFunc: func(ctx context.Context, info MigrationInfo, err error, duration time.Duration) {
+				afterCalled = true
+				afterInfo = info
+				afterErr = err
+				afterDuration = duration
+			},
+		},
+	}
+
+	wantErr := errors.New("boom")
+	info := MigrationInfo{Version: "1", Name: "test", Type: "tx"}
+	err := m.instrument(context.Background(), info, func() error {
+		time.Sleep(time.Millisecond)
+		return wantErr
+	})
+
+	if err != wantErr {
+		t.Fatalf("expected instrument to return fn's error unchanged, got %v", err)
+	}
+	if !beforeCalled || !afterCalled {
+		t.Fatal("expected both BeforeMigration and AfterMigration to be called")
+	}
+	if beforeInfo != info || afterInfo != info {
+		t.Fatalf("expected hooks to receive %+v, got before=%+v after=%+v", info, beforeInfo, afterInfo)
+	}
+	if afterErr != wantErr {
+		t.Fatalf("expected AfterMigration to receive the error, got %v", afterErr)
+	}
+	if afterDuration <= 0 {
+		t.Fatal("expected AfterMigration to receive a positive duration")
+	}
+}
+
+func TestHookFuncsNilFieldsAreNoops(t *testing.T) {
+	var h HookFuncs
+	h.BeforeAll(context.Background(), nil)
+	h.AfterAll(context.Background(), nil, nil)
+	h.BeforeMigration(context.Background(), MigrationInfo{})
+	h.AfterMigration(context.Background(), MigrationInfo{}, nil, 0)
+}
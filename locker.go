@@ -0,0 +1,128 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ErrLockTimeout is returned when a Locker could not acquire its lock within the
+// configured LockTimeout.
+var ErrLockTimeout = errors.New("migrator: timed out waiting for migration lock")
+
+// Locker serializes concurrent Migrate calls against the same database so that
+// multiple instances of an application starting at once (Kubernetes rollouts, etc.)
+// don't race over the migrations table. Lock and Unlock are always called on the same
+// *sql.Conn, since the session-scoped locks the built-in Lockers use (pg_advisory_lock,
+// GET_LOCK) are tied to the connection that acquired them.
+type Locker interface {
+	Lock(ctx context.Context, conn *sql.Conn) error
+	Unlock(ctx context.Context, conn *sql.Conn) error
+}
+
+// lockerFor returns the built-in Locker for db's underlying driver family, or nil if
+// it's not one migrator knows how to lock, in which case Migrate runs unlocked.
+func lockerFor(db *sql.DB, tableName string) Locker {
+	switch driverName(db) {
+	case "postgres":
+		return NewPostgresLocker(tableName)
+	case "mysql":
+		return NewMySQLLocker(tableName)
+	default:
+		return nil
+	}
+}
+
+// driverName returns a best-effort guess at the underlying database family ("postgres"
+// or "mysql") by inspecting the concrete type and package path of db's driver.Driver,
+// since database/sql doesn't expose the name a driver was registered under once a *sql.DB
+// exists. The package path is needed for drivers such as pgx, whose type is *stdlib.Driver.
+func driverName(db *sql.DB) string {
+	d := db.Driver()
+	t := strings.ToLower(fmt.Sprintf("%T", d))
+	if rt := reflect.TypeOf(d); rt != nil {
+		if rt.Kind() == reflect.Ptr {
+			rt = rt.Elem()
+		}
+		t += " " + strings.ToLower(rt.PkgPath())
+	}
+	switch {
+	case strings.Contains(t, "pgx"), strings.Contains(t, "postgres"), strings.Contains(t, "pq."):
+		return "postgres"
+	case strings.Contains(t, "mysql"):
+		return "mysql"
+	default:
+		return ""
+	}
+}
+
+// lockKeyFor derives a stable lock key from tableName so different migrators (e.g.
+// pointed at different tables in the same database) don't contend with one another.
+func lockKeyFor(tableName string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(tableName))
+	return int64(h.Sum64())
+}
+
+// postgresLocker acquires a session-level advisory lock via pg_advisory_lock.
+type postgresLocker struct {
+	key int64
+}
+
+// NewPostgresLocker creates a Locker backed by Postgres advisory locks.
+func NewPostgresLocker(tableName string) Locker {
+	return &postgresLocker{key: lockKeyFor(tableName)}
+}
+
+func (l *postgresLocker) Lock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", l.key)
+	return err
+}
+
+func (l *postgresLocker) Unlock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.key)
+	return err
+}
+
+// mysqlLocker acquires a named lock via GET_LOCK.
+type mysqlLocker struct {
+	name string
+}
+
+// NewMySQLLocker creates a Locker backed by MySQL named locks (GET_LOCK/RELEASE_LOCK).
+func NewMySQLLocker(tableName string) Locker {
+	return &mysqlLocker{name: "migrator_" + tableName}
+}
+
+func (l *mysqlLocker) Lock(ctx context.Context, conn *sql.Conn) error {
+	// GET_LOCK takes its own timeout in seconds rather than honoring ctx cancellation;
+	// derive it from ctx's deadline, if any, so LockTimeout still applies.
+	timeout := -1
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := int(time.Until(deadline).Seconds()); remaining > timeout {
+			timeout = remaining
+		}
+		if timeout < 0 {
+			timeout = 0
+		}
+	}
+
+	var result sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", l.name, timeout).Scan(&result); err != nil {
+		return err
+	}
+	if !result.Valid || result.Int64 != 1 {
+		return ErrLockTimeout
+	}
+	return nil
+}
+
+func (l *mysqlLocker) Unlock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", l.name)
+	return err
+}
@@ -0,0 +1,120 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+	"testing"
+)
+
+func TestLockKeyForIsStable(t *testing.T) {
+	a := lockKeyFor("migrations")
+	b := lockKeyFor("migrations")
+	if a != b {
+		t.Fatal("lockKeyFor should be deterministic for the same table name")
+	}
+	if c := lockKeyFor("other_migrations"); c == a {
+		t.Fatal("lockKeyFor should differ across table names")
+	}
+}
+
+// fakeConn, fakeStmt and fakeRows are a minimal database/sql/driver implementation used
+// only to obtain a *sql.Conn to exercise acquireLock against, without a real database.
+type fakeConn struct{}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+type fakeStmt struct{}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{}, nil
+}
+
+type fakeRows struct{ read bool }
+
+func (r *fakeRows) Columns() []string { return []string{"result"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.read {
+		return sql.ErrNoRows
+	}
+	r.read = true
+	dest[0] = int64(1)
+	return nil
+}
+
+type fakeDriver struct{}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) { return &fakeConn{}, nil }
+
+var registerFakeDriverOnce sync.Once
+
+func newFakeDB(t *testing.T) *sql.DB {
+	t.Helper()
+	registerFakeDriverOnce.Do(func() {
+		sql.Register("migrator-fake", &fakeDriver{})
+	})
+	db, err := sql.Open("migrator-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+// recordingLocker records the *sql.Conn it was called with, so tests can assert that
+// Lock and Unlock run against the same pinned connection.
+type recordingLocker struct {
+	lockConn, unlockConn *sql.Conn
+}
+
+func (l *recordingLocker) Lock(ctx context.Context, conn *sql.Conn) error {
+	l.lockConn = conn
+	return nil
+}
+
+func (l *recordingLocker) Unlock(ctx context.Context, conn *sql.Conn) error {
+	l.unlockConn = conn
+	return nil
+}
+
+func TestAcquireLockPinsConnection(t *testing.T) {
+	db := newFakeDB(t)
+	locker := &recordingLocker{}
+	m := &Migrator{locker: locker}
+
+	release, err := m.acquireLock(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if locker.lockConn == nil {
+		t.Fatal("expected Lock to be called")
+	}
+
+	release()
+	if locker.unlockConn == nil {
+		t.Fatal("expected Unlock to be called")
+	}
+	if locker.lockConn != locker.unlockConn {
+		t.Fatal("expected Lock and Unlock to run against the same *sql.Conn")
+	}
+}
+
+func TestAcquireLockNoLockerIsNoop(t *testing.T) {
+	db := newFakeDB(t)
+	m := &Migrator{}
+
+	release, err := m.acquireLock(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+}
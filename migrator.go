@@ -7,27 +7,60 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"strings"
+	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const defaultTableName = "migrations"
 
+// tableNameRe restricts table names to a plain identifier, optionally schema-qualified,
+// so that a misconfigured TableName can't be used to inject arbitrary SQL into the DDL
+// and queries migrator builds by string concatenation.
+var tableNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// validateTableName returns an error if tableName isn't a safe, plain identifier.
+func validateTableName(tableName string) error {
+	if !tableNameRe.MatchString(tableName) {
+		return fmt.Errorf("migrator: invalid table name %q", tableName)
+	}
+	return nil
+}
+
 // Migrator is the migrator implementation
 type Migrator struct {
-	tableName  string
-	logger     Logger
-	migrations []interface{}
+	tableName         string
+	logger            Logger
+	migrations        []interface{}
+	allowOutOfOrder   bool
+	ignoreUnknown     bool
+	locker            Locker
+	lockTimeout       time.Duration
+	hooks             Hooks
+	meter             metric.Meter
+	durationHistogram metric.Float64Histogram
+	failureCounter    metric.Int64Counter
+	optErr            error
 }
 
 // Option sets options such migrations or table name.
 type Option func(*Migrator)
 
-// TableName creates an option to allow overriding the default table name
+// TableName creates an option to allow overriding the default table name. tableName must
+// be a plain identifier (optionally schema-qualified, e.g. "myschema.migrations");
+// anything else is rejected by New to avoid building unsafe SQL from it.
 func TableName(tableName string) Option {
 	return func(m *Migrator) {
+		if err := validateTableName(tableName); err != nil {
+			m.optErr = err
+			return
+		}
 		m.tableName = tableName
 	}
 }
@@ -59,6 +92,53 @@ func Migrations(migrations ...interface{}) Option {
 	}
 }
 
+// AllowOutOfOrder creates an option that lets Migrate apply a migration declared before
+// one that is already applied, instead of treating that ordering as an error.
+func AllowOutOfOrder() Option {
+	return func(m *Migrator) {
+		m.allowOutOfOrder = true
+	}
+}
+
+// IgnoreUnknown creates an option that tolerates applied versions in the migrations
+// table that are no longer present in the provided migration list, instead of failing.
+func IgnoreUnknown() Option {
+	return func(m *Migrator) {
+		m.ignoreUnknown = true
+	}
+}
+
+// WithLocker creates an option to override the Locker automatically selected from db's
+// driver; useful to disable locking altogether (pass a no-op Locker) or to support a
+// driver migrator doesn't detect on its own.
+func WithLocker(locker Locker) Option {
+	return func(m *Migrator) {
+		m.locker = locker
+	}
+}
+
+// LockTimeout creates an option bounding how long Migrate waits to acquire its Locker
+// before giving up with ErrLockTimeout. The default is to wait indefinitely.
+func LockTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) {
+		m.lockTimeout = timeout
+	}
+}
+
+// ChecksumMismatchError is returned by Migrate when an already applied migration's
+// stored checksum no longer matches the checksum of the migration defined in code,
+// meaning the migration was edited after it ran.
+type ChecksumMismatchError struct {
+	Version  string
+	Name     string
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("migrator: checksum mismatch for migration %s (%s): expected %q, got %q", e.Version, e.Name, e.Expected, e.Actual)
+}
+
 // New creates a new migrator instance
 func New(opts ...Option) (*Migrator, error) {
 	m := &Migrator{
@@ -69,117 +149,632 @@ func New(opts ...Option) (*Migrator, error) {
 		opt(m)
 	}
 
+	if m.optErr != nil {
+		return nil, m.optErr
+	}
+	if err := validateTableName(m.tableName); err != nil {
+		return nil, err
+	}
+
 	if len(m.migrations) == 0 {
 		return nil, errors.New("migrator: migrations must be provided")
 	}
 
-	for _, m := range m.migrations {
-		switch m.(type) {
+	seen := make(map[string]struct{}, len(m.migrations))
+	for _, migration := range m.migrations {
+		switch migration.(type) {
 		case *Migration:
 		case *MigrationNoTx:
 		default:
 			return nil, errors.New("migrator: invalid migration type")
 		}
+		version := versionOf(migration)
+		if version == "" {
+			return nil, errors.New("migrator: migration version must be provided")
+		}
+		if _, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrator: duplicate migration version %q", version)
+		}
+		seen[version] = struct{}{}
+	}
+
+	if err := m.initMeter(); err != nil {
+		return nil, fmt.Errorf("migrator: initializing metrics: %w", err)
 	}
 
 	return m, nil
 }
 
-// Migrate applies all available migrations
+// Migrate applies all pending migrations, matching applied state by version rather
+// than position. Versions already recorded in the migrations table are skipped; if one
+// of them no longer matches its code checksum, a *ChecksumMismatchError is returned.
 func (m *Migrator) Migrate(ctx context.Context, db *sql.DB) error {
 	tracer := otel.Tracer("")
+	ctx, rootSpan := tracer.Start(ctx, "migrate")
+	defer rootSpan.End()
+
+	release, err := m.acquireLock(ctx, db)
+	if err != nil {
+		rootSpan.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	defer release()
+
+	return m.migrateUpTo(ctx, tracer, rootSpan, db, len(m.migrations))
+}
+
+// acquireLock obtains m.locker (or the Locker automatically detected for db's driver),
+// if any, pinning a single *sql.Conn for the lock's entire lifetime so that Unlock
+// always runs on the same physical connection that took the lock; acquiring and
+// releasing a session-scoped advisory lock (pg_advisory_lock, GET_LOCK) through a plain
+// *sql.DB would let the pool hand Lock and Unlock different connections, leaving the
+// lock held by a connection nobody ever unlocks. The returned release func unlocks and
+// releases the connection, and must always be called once acquireLock returns a nil
+// error; it is a no-op if no locker applies.
+func (m *Migrator) acquireLock(ctx context.Context, db *sql.DB) (release func(), err error) {
+	locker := m.locker
+	if locker == nil {
+		locker = lockerFor(db, m.tableName)
+	}
+	if locker == nil {
+		return func() {}, nil
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lockCtx := ctx
+	if m.lockTimeout > 0 {
+		var cancel context.CancelFunc
+		lockCtx, cancel = context.WithTimeout(ctx, m.lockTimeout)
+		defer cancel()
+	}
+	if err := locker.Lock(lockCtx, conn); err != nil {
+		_ = conn.Close()
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = ErrLockTimeout
+		}
+		return nil, err
+	}
+
+	return func() {
+		_ = locker.Unlock(ctx, conn)
+		_ = conn.Close()
+	}, nil
+}
+
+// MigrateTo applies or rolls back migrations as needed to bring the database to the
+// given target version, mirroring Migrate/Rollback depending on which direction is
+// required to get there.
+func (m *Migrator) MigrateTo(ctx context.Context, db *sql.DB, targetVersion string) error {
+	targetIdx := -1
+	for idx, migration := range m.migrations {
+		if versionOf(migration) == targetVersion {
+			targetIdx = idx
+			break
+		}
+	}
+	if targetIdx == -1 {
+		return fmt.Errorf("migrator: unknown target version %q", targetVersion)
+	}
+
+	if err := m.ensureTable(ctx, db); err != nil {
+		return err
+	}
 
-	// create migrations table if doesn't exist
+	applied, err := appliedRows(ctx, db, m.tableName)
+	if err != nil {
+		return err
+	}
+	appliedByVersion := make(map[string]struct{}, len(applied))
+	for _, row := range applied {
+		appliedByVersion[row.Version] = struct{}{}
+	}
+
+	if _, ok := appliedByVersion[targetVersion]; ok {
+		tracer := otel.Tracer("")
+		ctx, rootSpan := tracer.Start(ctx, "rollback")
+		defer rootSpan.End()
+
+		release, err := m.acquireLock(ctx, db)
+		if err != nil {
+			rootSpan.SetStatus(codes.Error, err.Error())
+			return err
+		}
+		defer release()
+
+		// Re-read applied state now that the lock is held: a concurrent Migrate,
+		// Rollback or MigrateTo may have run while this call waited for the lock, and
+		// acting on the snapshot read above could roll back too far or too little.
+		applied, err := appliedRows(ctx, db, m.tableName)
+		if err != nil {
+			rootSpan.SetStatus(codes.Error, err.Error())
+			return err
+		}
+		appliedByVersion := make(map[string]struct{}, len(applied))
+		for _, row := range applied {
+			appliedByVersion[row.Version] = struct{}{}
+		}
+		if _, ok := appliedByVersion[targetVersion]; !ok {
+			return m.migrateUpTo(ctx, tracer, rootSpan, db, targetIdx+1)
+		}
+		stepsBack := 0
+		for idx := targetIdx + 1; idx < len(m.migrations); idx++ {
+			if _, ok := appliedByVersion[versionOf(m.migrations[idx])]; ok {
+				stepsBack++
+			}
+		}
+		if stepsBack == 0 {
+			return nil
+		}
+		return m.rollbackSteps(ctx, tracer, rootSpan, db, stepsBack)
+	}
+
+	tracer := otel.Tracer("")
 	ctx, rootSpan := tracer.Start(ctx, "migrate")
 	defer rootSpan.End()
-	_, err := db.ExecContext(ctx, fmt.Sprintf(`
+
+	release, err := m.acquireLock(ctx, db)
+	if err != nil {
+		rootSpan.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	defer release()
+
+	return m.migrateUpTo(ctx, tracer, rootSpan, db, targetIdx+1)
+}
+
+// migrateUpTo applies every not-yet-applied migration declared before limitIdx in
+// m.migrations, after verifying that no already-applied migration's checksum has
+// drifted and that the database doesn't contain unknown versions (unless IgnoreUnknown
+// is set).
+func (m *Migrator) migrateUpTo(ctx context.Context, tracer trace.Tracer, rootSpan trace.Span, db *sql.DB, limitIdx int) (err error) {
+	if err = m.ensureTable(ctx, db); err != nil {
+		rootSpan.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	applied, err := appliedRows(ctx, db, m.tableName)
+	if err != nil {
+		rootSpan.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	appliedByVersion := make(map[string]appliedRow, len(applied))
+	for _, row := range applied {
+		appliedByVersion[row.Version] = row
+	}
+	rootSpan.SetAttributes(attribute.Int("applied", len(applied)))
+
+	if !m.ignoreUnknown {
+		known := make(map[string]struct{}, len(m.migrations))
+		for _, migration := range m.migrations {
+			known[versionOf(migration)] = struct{}{}
+		}
+		for _, row := range applied {
+			if _, ok := known[row.Version]; !ok {
+				err := fmt.Errorf("migrator: database contains unknown migration version %q", row.Version)
+				rootSpan.SetStatus(codes.Error, err.Error())
+				return err
+			}
+		}
+	}
+
+	lastAppliedIdx := -1
+	for idx, migration := range m.migrations {
+		row, ok := appliedByVersion[versionOf(migration)]
+		if !ok {
+			continue
+		}
+		lastAppliedIdx = idx
+		if checksum := checksumOf(migration); checksum != "" && row.Checksum != "" && row.Checksum != checksum {
+			err := &ChecksumMismatchError{Version: versionOf(migration), Name: nameOf(migration), Expected: row.Checksum, Actual: checksum}
+			rootSpan.SetStatus(codes.Error, err.Error())
+			return err
+		}
+	}
+
+	if m.hooks != nil {
+		var pending []MigrationInfo
+		for idx, migration := range m.migrations {
+			if idx >= limitIdx {
+				continue
+			}
+			if _, ok := appliedByVersion[versionOf(migration)]; ok {
+				continue
+			}
+			pending = append(pending, MigrationInfo{Version: versionOf(migration), Name: nameOf(migration), Type: typeOf(migration)})
+		}
+		m.hooks.BeforeAll(ctx, pending)
+		defer func() {
+			m.hooks.AfterAll(ctx, pending, err)
+		}()
+	}
+
+	for idx, migration := range m.migrations {
+		version, name, checksum := versionOf(migration), nameOf(migration), checksumOf(migration)
+
+		if _, ok := appliedByVersion[version]; ok {
+			continue
+		}
+		if idx >= limitIdx {
+			continue
+		}
+		if idx < lastAppliedIdx && !m.allowOutOfOrder {
+			err := fmt.Errorf("migrator: migration %s (%s) is declared before an already applied migration; enable AllowOutOfOrder to apply it anyway", version, name)
+			rootSpan.SetStatus(codes.Error, err.Error())
+			return err
+		}
+
+		info := MigrationInfo{Version: version, Name: name, Type: typeOf(migration)}
+		insertVersion := fmt.Sprintf("INSERT INTO %s (version, name, checksum, applied_at) VALUES (%s, now())", m.tableName, placeholders(driverName(db), 3))
+		insertArgs := []interface{}{version, name, checksum}
+		ctx, span := tracer.Start(ctx, "migration")
+		switch mig := migration.(type) {
+		case *Migration:
+			span.SetAttributes(attribute.String("type", "tx"))
+			span.SetAttributes(attribute.String("version", version))
+			span.SetAttributes(attribute.String("name", name))
+			if err := m.instrument(ctx, info, func() error { return migrate(ctx, db, m.logger, insertVersion, insertArgs, mig) }); err != nil {
+				span.SetStatus(codes.Error, err.Error())
+				span.End()
+				return fmt.Errorf("migrator: error while running migrations: %v", err)
+			}
+		case *MigrationNoTx:
+			span.SetAttributes(attribute.String("type", "no-tx"))
+			span.SetAttributes(attribute.String("version", version))
+			span.SetAttributes(attribute.String("name", name))
+			if err := m.instrument(ctx, info, func() error { return migrateNoTx(ctx, db, m.logger, insertVersion, insertArgs, mig) }); err != nil {
+				span.SetStatus(codes.Error, err.Error())
+				span.End()
+				return fmt.Errorf("migrator: error while running migrations: %v", err)
+			}
+		}
+		span.SetAttributes(attribute.Int("number", idx))
+		span.SetStatus(codes.Ok, "")
+		span.End()
+	}
+
+	rootSpan.SetStatus(codes.Ok, "migrations applied successfully")
+
+	return nil
+}
+
+// ensureTable creates the migrations table if it doesn't already exist.
+func (m *Migrator) ensureTable(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(`
 		CREATE TABLE IF NOT EXISTS %s (
-			id INT8 NOT NULL,
 			version VARCHAR(255) NOT NULL,
-			PRIMARY KEY (id)
+			name VARCHAR(255) NOT NULL DEFAULT '',
+			checksum VARCHAR(255) NOT NULL DEFAULT '',
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (version)
 		);
-	`, m.tableName))
+	`, m.tableName)); err != nil {
+		return err
+	}
+
+	return m.upgradeLegacyTable(ctx, db)
+}
+
+// upgradeLegacyTable adds the name, checksum and applied_at columns to a migrations
+// table created by a pre-version-tracking release of this library, which only had (id,
+// version). Existing rows are backfilled with an empty checksum, so the checksum-drift
+// check is skipped for migrations applied before the upgrade, and the current time as
+// applied_at. It is a no-op once the table already has those columns.
+func (m *Migrator) upgradeLegacyTable(ctx context.Context, db *sql.DB) error {
+	cols, err := tableColumns(ctx, db, m.tableName)
+	if err != nil {
+		return err
+	}
+	if cols["name"] && cols["checksum"] && cols["applied_at"] {
+		return nil
+	}
+
+	if !cols["name"] {
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN name VARCHAR(255) NOT NULL DEFAULT ''", m.tableName)); err != nil {
+			return fmt.Errorf("migrator: upgrading legacy migrations table: %w", err)
+		}
+	}
+	if !cols["checksum"] {
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN checksum VARCHAR(255) NOT NULL DEFAULT ''", m.tableName)); err != nil {
+			return fmt.Errorf("migrator: upgrading legacy migrations table: %w", err)
+		}
+	}
+	if !cols["applied_at"] {
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP", m.tableName)); err != nil {
+			return fmt.Errorf("migrator: upgrading legacy migrations table: %w", err)
+		}
+	}
+	return nil
+}
+
+// tableColumns returns the set of column names present in tableName, using
+// information_schema.columns (so the same query works against both Postgres and MySQL),
+// scoped to tableName's schema (the part before a ".", or the connection's current
+// schema/database if unqualified) so that a same-named table in a different schema
+// can't be mistaken for it.
+func tableColumns(ctx context.Context, db *sql.DB, tableName string) (map[string]bool, error) {
+	driver := driverName(db)
+	name := tableName
+	var args []interface{}
+	var schemaClause string
+
+	if idx := strings.LastIndex(tableName, "."); idx >= 0 {
+		schema := tableName[:idx]
+		name = tableName[idx+1:]
+		args = append(args, name, schema)
+		schemaClause = fmt.Sprintf("table_schema = %s", placeholderAt(driver, 2))
+	} else {
+		args = append(args, name)
+		schemaClause = "table_schema = " + currentSchemaSQL(driver)
+	}
+
+	query := fmt.Sprintf(
+		"SELECT column_name FROM information_schema.columns WHERE table_name = %s AND %s",
+		placeholderAt(driver, 1), schemaClause,
+	)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	cols := make(map[string]bool)
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			return nil, err
+		}
+		cols[col] = true
+	}
+	return cols, rows.Err()
+}
+
+// Rollback undoes up to steps previously applied migrations, running each migration's
+// Down function in reverse (most recently applied first) and removing its row from the
+// migrations table.
+func (m *Migrator) Rollback(ctx context.Context, db *sql.DB, steps int) error {
+	tracer := otel.Tracer("")
+	ctx, rootSpan := tracer.Start(ctx, "rollback")
+	defer rootSpan.End()
+
+	if steps <= 0 {
+		err := errors.New("migrator: steps must be greater than 0")
+		rootSpan.SetStatus(codes.Error, err.Error())
 		return err
 	}
 
-	// count applied migrations
-	count, err := countApplied(ctx, db, m.tableName)
+	release, err := m.acquireLock(ctx, db)
 	if err != nil {
+		rootSpan.SetStatus(codes.Error, err.Error())
 		return err
 	}
-	rootSpan.SetAttributes(attribute.Int("applied", count))
+	defer release()
+
+	return m.rollbackSteps(ctx, tracer, rootSpan, db, steps)
+}
 
-	if count > len(m.migrations) {
-		err := errors.New("migrator: applied migration number on db cannot be greater than the defined migration list")
+// rollbackSteps does the work of Rollback, assuming the caller already holds m's lock
+// (if any) and has already started and will end rootSpan. It's split out so MigrateTo's
+// down path can recompute steps under a single, already-held lock instead of taking the
+// lock a second time via Rollback.
+func (m *Migrator) rollbackSteps(ctx context.Context, tracer trace.Tracer, rootSpan trace.Span, db *sql.DB, steps int) (err error) {
+	applied, err := appliedRows(ctx, db, m.tableName)
+	if err != nil {
 		rootSpan.SetStatus(codes.Error, err.Error())
 		return err
 	}
+	if steps > len(applied) {
+		steps = len(applied)
+	}
+	rootSpan.SetAttributes(attribute.Int("steps", steps))
 
-	// plan migrations
-	for idx, migration := range m.migrations[count:len(m.migrations)] {
-		insertVersion := fmt.Sprintf("INSERT INTO %s (id, version) VALUES (%d, '%s')", m.tableName, idx+count, migration.(fmt.Stringer).String())
-		ctx, span := tracer.Start(ctx, "migration")
-		defer span.End()
+	byVersion := make(map[string]interface{}, len(m.migrations))
+	for _, migration := range m.migrations {
+		byVersion[versionOf(migration)] = migration
+	}
+
+	toRollback := applied[len(applied)-steps:]
+
+	if m.hooks != nil {
+		infos := make([]MigrationInfo, 0, len(toRollback))
+		for i := len(toRollback) - 1; i >= 0; i-- {
+			row := toRollback[i]
+			info := MigrationInfo{Version: row.Version, Name: row.Name}
+			if migration, ok := byVersion[row.Version]; ok {
+				info.Type = typeOf(migration)
+			}
+			infos = append(infos, info)
+		}
+		m.hooks.BeforeAll(ctx, infos)
+		defer func() {
+			m.hooks.AfterAll(ctx, infos, err)
+		}()
+	}
+
+	for i := len(toRollback) - 1; i >= 0; i-- {
+		row := toRollback[i]
+		migration, ok := byVersion[row.Version]
+		if !ok {
+			err := fmt.Errorf("migrator: cannot roll back unknown migration version %q", row.Version)
+			rootSpan.SetStatus(codes.Error, err.Error())
+			return err
+		}
+
+		info := MigrationInfo{Version: row.Version, Name: nameOf(migration), Type: typeOf(migration)}
+		deleteVersion := fmt.Sprintf("DELETE FROM %s WHERE version = %s", m.tableName, placeholders(driverName(db), 1))
+		deleteArgs := []interface{}{row.Version}
+		ctx, span := tracer.Start(ctx, "rollback_migration")
 		switch mig := migration.(type) {
 		case *Migration:
 			span.SetAttributes(attribute.String("type", "tx"))
+			span.SetAttributes(attribute.String("version", row.Version))
 			span.SetAttributes(attribute.String("name", mig.Name))
-			if err := migrate(ctx, db, m.logger, insertVersion, mig); err != nil {
+			if err := m.instrument(ctx, info, func() error { return rollback(ctx, db, m.logger, deleteVersion, deleteArgs, mig) }); err != nil {
 				span.SetStatus(codes.Error, err.Error())
-				return fmt.Errorf("migrator: error while running migrations: %v", err)
+				span.End()
+				return fmt.Errorf("migrator: error while rolling back migrations: %v", err)
 			}
 		case *MigrationNoTx:
 			span.SetAttributes(attribute.String("type", "no-tx"))
+			span.SetAttributes(attribute.String("version", row.Version))
 			span.SetAttributes(attribute.String("name", mig.Name))
-			if err := migrateNoTx(ctx, db, m.logger, insertVersion, mig); err != nil {
+			if err := m.instrument(ctx, info, func() error { return rollbackNoTx(ctx, db, m.logger, deleteVersion, deleteArgs, mig) }); err != nil {
 				span.SetStatus(codes.Error, err.Error())
-				return fmt.Errorf("migrator: error while running migrations: %v", err)
+				span.End()
+				return fmt.Errorf("migrator: error while rolling back migrations: %v", err)
 			}
 		}
-		span.SetAttributes(attribute.Int("number", idx))
 		span.SetStatus(codes.Ok, "")
+		span.End()
 	}
 
-	rootSpan.SetStatus(codes.Ok, "migrations applied successfully")
+	rootSpan.SetStatus(codes.Ok, "migrations rolled back successfully")
 
 	return nil
 }
 
 // Pending returns all pending (not yet applied) migrations
 func (m *Migrator) Pending(db *sql.DB) ([]interface{}, error) {
-	count, err := countApplied(context.Background(), db, m.tableName)
+	applied, err := appliedRows(context.Background(), db, m.tableName)
 	if err != nil {
 		return nil, err
 	}
-	return m.migrations[count:len(m.migrations)], nil
+	appliedVersions := make(map[string]struct{}, len(applied))
+	for _, row := range applied {
+		appliedVersions[row.Version] = struct{}{}
+	}
+
+	var pending []interface{}
+	for _, migration := range m.migrations {
+		if _, ok := appliedVersions[versionOf(migration)]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
 }
 
-func countApplied(ctx context.Context, db *sql.DB, tableName string) (int, error) {
-	// count applied migrations
-	var count int
-	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT count(*) FROM %s", tableName))
+// appliedRow is a row of the migrations table.
+type appliedRow struct {
+	Version   string
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// appliedRows returns the rows of the migrations table, ordered by application time.
+func appliedRows(ctx context.Context, db *sql.DB, tableName string) ([]appliedRow, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT version, name, checksum, applied_at FROM %s ORDER BY applied_at ASC", tableName))
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 	defer func() {
 		_ = rows.Close()
 	}()
+	var applied []appliedRow
 	for rows.Next() {
-		if err := rows.Scan(&count); err != nil {
-			return 0, err
+		var row appliedRow
+		if err := rows.Scan(&row.Version, &row.Name, &row.Checksum, &row.AppliedAt); err != nil {
+			return nil, err
 		}
+		applied = append(applied, row)
 	}
 	if err := rows.Err(); err != nil {
-		return 0, err
+		return nil, err
+	}
+	return applied, nil
+}
+
+// versionOf, nameOf and checksumOf read the common fields shared by Migration and
+// MigrationNoTx without requiring them to implement a public interface.
+func versionOf(migration interface{}) string {
+	switch mig := migration.(type) {
+	case *Migration:
+		return mig.Version
+	case *MigrationNoTx:
+		return mig.Version
+	}
+	return ""
+}
+
+func nameOf(migration interface{}) string {
+	switch mig := migration.(type) {
+	case *Migration:
+		return mig.Name
+	case *MigrationNoTx:
+		return mig.Name
+	}
+	return ""
+}
+
+func checksumOf(migration interface{}) string {
+	switch mig := migration.(type) {
+	case *Migration:
+		return mig.Checksum
+	case *MigrationNoTx:
+		return mig.Checksum
+	}
+	return ""
+}
+
+// placeholders builds a comma-separated list of n bind-variable placeholders in the
+// style driver uses ("$1, $2, ..." for postgres, "?, ?, ..." otherwise), so callers can
+// parameterize queries instead of interpolating values into the SQL string.
+func placeholders(driver string, n int) string {
+	ph := make([]string, n)
+	for i := 0; i < n; i++ {
+		ph[i] = placeholderAt(driver, i+1)
+	}
+	return strings.Join(ph, ", ")
+}
+
+// placeholderAt returns the bind-variable placeholder for the pos'th (1-based) argument
+// in driver's style ("$pos" for postgres, "?" otherwise, since MySQL placeholders are
+// positional rather than numbered).
+func placeholderAt(driver string, pos int) string {
+	if driver == "postgres" {
+		return fmt.Sprintf("$%d", pos)
+	}
+	return "?"
+}
+
+// currentSchemaSQL returns the SQL expression for the schema/database an unqualified
+// table name would resolve to in driver, for scoping an information_schema lookup when
+// the configured table name wasn't explicitly schema-qualified.
+func currentSchemaSQL(driver string) string {
+	if driver == "postgres" {
+		return "current_schema()"
+	}
+	return "DATABASE()"
+}
+
+// typeOf returns "tx" for *Migration and "no-tx" for *MigrationNoTx.
+func typeOf(migration interface{}) string {
+	switch migration.(type) {
+	case *Migration:
+		return "tx"
+	case *MigrationNoTx:
+		return "no-tx"
 	}
-	return count, nil
+	return ""
 }
 
 // Migration represents a single migration
 type Migration struct {
-	Name string
-	Func func(*sql.Tx) error
+	// Version uniquely identifies the migration; applied state is matched against it
+	// rather than against the migration's position in the list.
+	Version string
+	Name    string
+	// Checksum, when set, is compared against the checksum stored at apply time; a
+	// mismatch means the migration was edited after it ran and Migrate returns a
+	// *ChecksumMismatchError instead of silently reapplying or ignoring it.
+	Checksum string
+	Func     func(*sql.Tx) error
+	// Down reverts the migration, used by Migrator.Rollback and Migrator.MigrateTo.
+	Down func(*sql.Tx) error
 }
 
 // String returns a string representation of the migration
@@ -189,15 +784,24 @@ func (m *Migration) String() string {
 
 // MigrationNoTx represents a single not transactional migration
 type MigrationNoTx struct {
-	Name string
-	Func func(*sql.DB) error
+	// Version uniquely identifies the migration; applied state is matched against it
+	// rather than against the migration's position in the list.
+	Version string
+	Name    string
+	// Checksum, when set, is compared against the checksum stored at apply time; a
+	// mismatch means the migration was edited after it ran and Migrate returns a
+	// *ChecksumMismatchError instead of silently reapplying or ignoring it.
+	Checksum string
+	Func     func(*sql.DB) error
+	// Down reverts the migration, used by Migrator.Rollback and Migrator.MigrateTo.
+	Down func(*sql.DB) error
 }
 
 func (m *MigrationNoTx) String() string {
 	return m.Name
 }
 
-func migrate(ctx context.Context, db *sql.DB, logger Logger, insertVersion string, migration *Migration) error {
+func migrate(ctx context.Context, db *sql.DB, logger Logger, insertVersion string, insertArgs []interface{}, migration *Migration) error {
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
@@ -215,7 +819,7 @@ func migrate(ctx context.Context, db *sql.DB, logger Logger, insertVersion strin
 	if err = migration.Func(tx); err != nil {
 		return fmt.Errorf("error executing golang migration: %s", err)
 	}
-	if _, err = tx.ExecContext(ctx, insertVersion); err != nil {
+	if _, err = tx.ExecContext(ctx, insertVersion, insertArgs...); err != nil {
 		return fmt.Errorf("error updating migration versions: %s", err)
 	}
 	logger.Printf("applied migration named '%s'", migration.Name)
@@ -223,15 +827,60 @@ func migrate(ctx context.Context, db *sql.DB, logger Logger, insertVersion strin
 	return err
 }
 
-func migrateNoTx(ctx context.Context, db *sql.DB, logger Logger, insertVersion string, migration *MigrationNoTx) error {
+func migrateNoTx(ctx context.Context, db *sql.DB, logger Logger, insertVersion string, insertArgs []interface{}, migration *MigrationNoTx) error {
 	logger.Printf("applying no tx migration named '%s'...", migration.Name)
 	if err := migration.Func(db); err != nil {
 		return fmt.Errorf("error executing golang migration: %s", err)
 	}
-	if _, err := db.ExecContext(ctx, insertVersion); err != nil {
+	if _, err := db.ExecContext(ctx, insertVersion, insertArgs...); err != nil {
 		return fmt.Errorf("error updating migration versions: %s", err)
 	}
 	logger.Printf("applied no tx migration named '%s'", migration.Name)
 
 	return nil
 }
+
+func rollback(ctx context.Context, db *sql.DB, logger Logger, deleteVersion string, deleteArgs []interface{}, migration *Migration) error {
+	if migration.Down == nil {
+		return fmt.Errorf("migrator: migration %q has no Down function", migration.Name)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if errRb := tx.Rollback(); errRb != nil {
+				err = fmt.Errorf("error rolling back: %s\n%s", errRb, err)
+			}
+			return
+		}
+		err = tx.Commit()
+	}()
+	logger.Printf("rolling back migration named '%s'...", migration.Name)
+	if err = migration.Down(tx); err != nil {
+		return fmt.Errorf("error executing golang migration: %s", err)
+	}
+	if _, err = tx.ExecContext(ctx, deleteVersion, deleteArgs...); err != nil {
+		return fmt.Errorf("error updating migration versions: %s", err)
+	}
+	logger.Printf("rolled back migration named '%s'", migration.Name)
+
+	return err
+}
+
+func rollbackNoTx(ctx context.Context, db *sql.DB, logger Logger, deleteVersion string, deleteArgs []interface{}, migration *MigrationNoTx) error {
+	if migration.Down == nil {
+		return fmt.Errorf("migrator: migration %q has no Down function", migration.Name)
+	}
+	logger.Printf("rolling back no tx migration named '%s'...", migration.Name)
+	if err := migration.Down(db); err != nil {
+		return fmt.Errorf("error executing golang migration: %s", err)
+	}
+	if _, err := db.ExecContext(ctx, deleteVersion, deleteArgs...); err != nil {
+		return fmt.Errorf("error updating migration versions: %s", err)
+	}
+	logger.Printf("rolled back no tx migration named '%s'", migration.Name)
+
+	return nil
+}
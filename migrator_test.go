@@ -6,10 +6,12 @@ import (
 	"context"
 	"database/sql"
 	_ "embed"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"testing"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql" // mysql driver
 	_ "github.com/jackc/pgx/v4/stdlib" // postgres driver
@@ -24,7 +26,8 @@ var mig0bar string
 
 var migrations = []interface{}{
 	&Migration{
-		Name: "Using tx, encapsulate two queries",
+		Version: "0",
+		Name:    "Using tx, encapsulate two queries",
 		Func: func(tx *sql.Tx) error {
 			if _, err := tx.Exec("CREATE TABLE foo (id INT PRIMARY KEY)"); err != nil {
 				return err
@@ -36,7 +39,8 @@ var migrations = []interface{}{
 		},
 	},
 	&MigrationNoTx{
-		Name: "Using db, execute one query",
+		Version: "1",
+		Name:    "Using db, execute one query",
 		Func: func(db *sql.DB) error {
 			if _, err := db.Exec("INSERT INTO foo (id) VALUES (2)"); err != nil {
 				return err
@@ -45,7 +49,8 @@ var migrations = []interface{}{
 		},
 	},
 	&Migration{
-		Name: "Using tx, one embedded query",
+		Version: "2",
+		Name:    "Using tx, one embedded query",
 		Func: func(tx *sql.Tx) error {
 			if _, err := tx.Exec(mig0bar); err != nil {
 				return err
@@ -97,17 +102,17 @@ func TestMigrationNumber(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	count, err := countApplied(context.Background(), db, defaultTableName)
+	applied, err := appliedRows(context.Background(), db, defaultTableName)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if count != 3 {
+	if len(applied) != 3 {
 		t.Fatal("db applied migration number should be 3")
 	}
 }
 
 func TestDatabaseNotFound(t *testing.T) {
-	migrator, err := New(Migrations(&Migration{}))
+	migrator, err := New(Migrations(&Migration{Version: "0"}))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -135,7 +140,8 @@ func TestBadMigrations(t *testing.T) {
 		{
 			name: "bad tx migration",
 			input: mustMigrator(New(Migrations(&Migration{
-				Name: "bad tx migration",
+				Version: "bad-tx",
+				Name:    "bad tx migration",
 				Func: func(tx *sql.Tx) error {
 					if _, err := tx.Exec("FAIL FAST"); err != nil {
 						return err
@@ -147,7 +153,8 @@ func TestBadMigrations(t *testing.T) {
 		{
 			name: "bad db migration",
 			input: mustMigrator(New(Migrations(&MigrationNoTx{
-				Name: "bad db migration",
+				Version: "bad-db",
+				Name:    "bad db migration",
 				Func: func(db *sql.DB) error {
 					if _, err := db.Exec("FAIL FAST"); err != nil {
 						return err
@@ -173,7 +180,7 @@ func TestBadMigrate(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if err := migrate(context.Background(), db, log.New(os.Stdout, "migrator: ", 0), "BAD INSERT VERSION", &Migration{Name: "bad insert version", Func: func(tx *sql.Tx) error {
+	if err := migrate(context.Background(), db, log.New(os.Stdout, "migrator: ", 0), "BAD INSERT VERSION", nil, &Migration{Version: "bad-insert", Name: "bad insert version", Func: func(tx *sql.Tx) error {
 		return nil
 	}}); err == nil {
 		t.Fatal("BAD INSERT VERSION should fail!")
@@ -185,7 +192,7 @@ func TestBadMigrateNoTx(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if err := migrateNoTx(context.Background(), db, log.New(os.Stdout, "migrator: ", 0), "BAD INSERT VERSION", &MigrationNoTx{Name: "bad migrate no tx", Func: func(db *sql.DB) error {
+	if err := migrateNoTx(context.Background(), db, log.New(os.Stdout, "migrator: ", 0), "BAD INSERT VERSION", nil, &MigrationNoTx{Version: "bad-no-tx", Name: "bad migrate no tx", Func: func(db *sql.DB) error {
 		return nil
 	}}); err == nil {
 		t.Fatal("BAD INSERT VERSION should fail!")
@@ -199,7 +206,8 @@ func TestBadMigrationNumber(t *testing.T) {
 	}
 	migrator := mustMigrator(New(Migrations(
 		&Migration{
-			Name: "bad migration number",
+			Version: "bad-migration-number",
+			Name:    "bad migration number",
 			Func: func(tx *sql.Tx) error {
 				if _, err := tx.Exec("CREATE TABLE bar (id INT PRIMARY KEY)"); err != nil {
 					return err
@@ -220,7 +228,8 @@ func TestPending(t *testing.T) {
 	}
 	migrator := mustMigrator(New(Migrations(
 		&Migration{
-			Name: "Using tx, create baz table",
+			Version: "baz",
+			Name:    "Using tx, create baz table",
 			Func: func(tx *sql.Tx) error {
 				if _, err := tx.Exec("CREATE TABLE baz (id INT PRIMARY KEY)"); err != nil {
 					return err
@@ -238,6 +247,43 @@ func TestPending(t *testing.T) {
 	}
 }
 
+func TestStatusAndPlan(t *testing.T) {
+	db, err := sql.Open("pgx", os.Getenv("POSTGRES_URL"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator := mustMigrator(New(Migrations(append(migrations, &Migration{
+		Version: "qux",
+		Name:    "Using tx, create qux table",
+		Func: func(tx *sql.Tx) error {
+			if _, err := tx.Exec("CREATE TABLE qux (id INT PRIMARY KEY)"); err != nil {
+				return err
+			}
+			return nil
+		},
+	})...)))
+
+	statuses, err := migrator.Status(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != len(migrations)+1 {
+		t.Fatalf("expected %d statuses, got %d", len(migrations)+1, len(statuses))
+	}
+	last := statuses[len(statuses)-1]
+	if last.Version != "qux" || last.Applied {
+		t.Fatalf("expected qux to be pending, got %+v", last)
+	}
+
+	plan, err := migrator.Plan(context.Background(), db, Up)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) != 1 || plan[0].Version != "qux" {
+		t.Fatalf("expected a single planned step for qux, got %+v", plan)
+	}
+}
+
 func TestTraces(t *testing.T) {
 	// Create a test span recorder.
 	sr := tracetest.NewSpanRecorder()
@@ -256,7 +302,8 @@ func TestTraces(t *testing.T) {
 	}
 	migrator := mustMigrator(New(Migrations(
 		&Migration{
-			Name: "testing trace",
+			Version: "trace",
+			Name:    "testing trace",
 			Func: func(tx *sql.Tx) error {
 				if _, err := tx.Exec("CREATE TABLE trace (id INT PRIMARY KEY)"); err != nil {
 					return err
@@ -296,10 +343,13 @@ func TestTraces(t *testing.T) {
 	if childSpanAttributes[0].Key != attribute.Key("type") {
 		t.Fatalf("Expected child span to have attribute 'type'")
 	}
-	if childSpanAttributes[1].Key != attribute.Key("name") {
+	if childSpanAttributes[1].Key != attribute.Key("version") {
+		t.Fatalf("Expected child span to have attribute 'version'")
+	}
+	if childSpanAttributes[2].Key != attribute.Key("name") {
 		t.Fatalf("Expected child span to have attribute 'name'")
 	}
-	if childSpanAttributes[2].Key != attribute.Key("number") {
+	if childSpanAttributes[3].Key != attribute.Key("number") {
 		t.Fatalf("Expected child span to have attribute 'number'")
 	}
 
@@ -308,3 +358,370 @@ func TestTraces(t *testing.T) {
 		t.Error("Child span does not have the correct parent span")
 	}
 }
+
+func TestRollback(t *testing.T) {
+	db, err := sql.Open("pgx", os.Getenv("POSTGRES_URL"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator := mustMigrator(New(Migrations(
+		&Migration{
+			Version: "rollback-a",
+			Name:    "create rollback_a",
+			Func: func(tx *sql.Tx) error {
+				_, err := tx.Exec("CREATE TABLE rollback_a (id INT PRIMARY KEY)")
+				return err
+			},
+			Down: func(tx *sql.Tx) error {
+				_, err := tx.Exec("DROP TABLE rollback_a")
+				return err
+			},
+		},
+		&Migration{
+			Version: "rollback-b",
+			Name:    "create rollback_b",
+			Func: func(tx *sql.Tx) error {
+				_, err := tx.Exec("CREATE TABLE rollback_b (id INT PRIMARY KEY)")
+				return err
+			},
+			Down: func(tx *sql.Tx) error {
+				_, err := tx.Exec("DROP TABLE rollback_b")
+				return err
+			},
+		},
+	), IgnoreUnknown()))
+	if err := migrator.Migrate(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrator.Rollback(context.Background(), db, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Exec("SELECT 1 FROM rollback_b"); err == nil {
+		t.Fatal("expected rollback_b to have been dropped by Rollback")
+	}
+	if _, err := db.Exec("SELECT 1 FROM rollback_a"); err != nil {
+		t.Fatalf("expected rollback_a to still exist: %v", err)
+	}
+
+	applied, err := appliedRows(context.Background(), db, defaultTableName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range applied {
+		if row.Version == "rollback-b" {
+			t.Fatal("expected rollback-b to no longer be recorded as applied")
+		}
+	}
+}
+
+func TestMigrateTo(t *testing.T) {
+	db, err := sql.Open("pgx", os.Getenv("POSTGRES_URL"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	migs := []interface{}{
+		&Migration{
+			Version: "to-a",
+			Name:    "create migrate_to_a",
+			Func: func(tx *sql.Tx) error {
+				_, err := tx.Exec("CREATE TABLE migrate_to_a (id INT PRIMARY KEY)")
+				return err
+			},
+			Down: func(tx *sql.Tx) error {
+				_, err := tx.Exec("DROP TABLE migrate_to_a")
+				return err
+			},
+		},
+		&Migration{
+			Version: "to-b",
+			Name:    "create migrate_to_b",
+			Func: func(tx *sql.Tx) error {
+				_, err := tx.Exec("CREATE TABLE migrate_to_b (id INT PRIMARY KEY)")
+				return err
+			},
+			Down: func(tx *sql.Tx) error {
+				_, err := tx.Exec("DROP TABLE migrate_to_b")
+				return err
+			},
+		},
+	}
+	migrator := mustMigrator(New(Migrations(migs...), IgnoreUnknown()))
+
+	if err := migrator.MigrateTo(context.Background(), db, "to-b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("SELECT 1 FROM migrate_to_b"); err != nil {
+		t.Fatalf("expected migrate_to_b to exist: %v", err)
+	}
+
+	if err := migrator.MigrateTo(context.Background(), db, "to-a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("SELECT 1 FROM migrate_to_b"); err == nil {
+		t.Fatal("expected migrate_to_b to have been rolled back by MigrateTo")
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	db, err := sql.Open("pgx", os.Getenv("POSTGRES_URL"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := mustMigrator(New(Migrations(&Migration{
+		Version:  "checksum-a",
+		Name:     "checksum test",
+		Checksum: "original",
+		Func: func(tx *sql.Tx) error {
+			_, err := tx.Exec("CREATE TABLE checksum_a (id INT PRIMARY KEY)")
+			return err
+		},
+	}), IgnoreUnknown()))
+	if err := original.Migrate(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+
+	drifted := mustMigrator(New(Migrations(&Migration{
+		Version:  "checksum-a",
+		Name:     "checksum test",
+		Checksum: "drifted",
+		Func: func(tx *sql.Tx) error {
+			return nil
+		},
+	}), IgnoreUnknown()))
+	err = drifted.Migrate(context.Background(), db)
+	var mismatch *ChecksumMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected a *ChecksumMismatchError, got %v", err)
+	}
+}
+
+func TestAllowOutOfOrder(t *testing.T) {
+	db, err := sql.Open("pgx", os.Getenv("POSTGRES_URL"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := mustMigrator(New(Migrations(&Migration{
+		Version: "ooo-b",
+		Name:    "create ooo_b",
+		Func: func(tx *sql.Tx) error {
+			_, err := tx.Exec("CREATE TABLE ooo_b (id INT PRIMARY KEY)")
+			return err
+		},
+	}), IgnoreUnknown()))
+	if err := first.Migrate(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+
+	outOfOrder := mustMigrator(New(Migrations(
+		&Migration{
+			Version: "ooo-a",
+			Name:    "create ooo_a",
+			Func: func(tx *sql.Tx) error {
+				_, err := tx.Exec("CREATE TABLE ooo_a (id INT PRIMARY KEY)")
+				return err
+			},
+		},
+		&Migration{
+			Version: "ooo-b",
+			Name:    "create ooo_b",
+			Func: func(tx *sql.Tx) error {
+				return nil
+			},
+		},
+	), IgnoreUnknown()))
+	if err := outOfOrder.Migrate(context.Background(), db); err == nil {
+		t.Fatal("expected an out-of-order migration to fail without AllowOutOfOrder")
+	}
+
+	allowed := mustMigrator(New(
+		Migrations(
+			&Migration{
+				Version: "ooo-a",
+				Name:    "create ooo_a",
+				Func: func(tx *sql.Tx) error {
+					_, err := tx.Exec("CREATE TABLE ooo_a (id INT PRIMARY KEY)")
+					return err
+				},
+			},
+			&Migration{
+				Version: "ooo-b",
+				Name:    "create ooo_b",
+				Func: func(tx *sql.Tx) error {
+					return nil
+				},
+			},
+		),
+		AllowOutOfOrder(),
+		IgnoreUnknown(),
+	))
+	if err := allowed.Migrate(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("SELECT 1 FROM ooo_a"); err != nil {
+		t.Fatalf("expected ooo_a to have been applied: %v", err)
+	}
+}
+
+func TestStatusChecksumMismatch(t *testing.T) {
+	db, err := sql.Open("pgx", os.Getenv("POSTGRES_URL"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := mustMigrator(New(Migrations(&Migration{
+		Version:  "status-checksum-a",
+		Name:     "checksum test",
+		Checksum: "original",
+		Func: func(tx *sql.Tx) error {
+			_, err := tx.Exec("CREATE TABLE status_checksum_a (id INT PRIMARY KEY)")
+			return err
+		},
+	}), IgnoreUnknown()))
+	if err := original.Migrate(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+
+	drifted := mustMigrator(New(Migrations(&Migration{
+		Version:  "status-checksum-a",
+		Name:     "checksum test",
+		Checksum: "drifted",
+		Func: func(tx *sql.Tx) error {
+			return nil
+		},
+	})))
+	statuses, err := drifted.Status(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || !statuses[0].ChecksumMismatch {
+		t.Fatalf("expected the drifted migration to report a checksum mismatch, got %+v", statuses)
+	}
+}
+
+func TestPlanOutOfOrder(t *testing.T) {
+	db, err := sql.Open("pgx", os.Getenv("POSTGRES_URL"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := mustMigrator(New(Migrations(&Migration{
+		Version: "plan-ooo-b",
+		Name:    "create plan_ooo_b",
+		Func: func(tx *sql.Tx) error {
+			_, err := tx.Exec("CREATE TABLE plan_ooo_b (id INT PRIMARY KEY)")
+			return err
+		},
+	}), IgnoreUnknown()))
+	if err := first.Migrate(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+
+	outOfOrder := mustMigrator(New(Migrations(
+		&Migration{
+			Version: "plan-ooo-a",
+			Name:    "create plan_ooo_a",
+			Func: func(tx *sql.Tx) error {
+				_, err := tx.Exec("CREATE TABLE plan_ooo_a (id INT PRIMARY KEY)")
+				return err
+			},
+		},
+		&Migration{
+			Version: "plan-ooo-b",
+			Name:    "create plan_ooo_b",
+			Func: func(tx *sql.Tx) error {
+				return nil
+			},
+		},
+	)))
+	if _, err := outOfOrder.Plan(context.Background(), db, Up); err == nil {
+		t.Fatal("expected Plan to reject a step Migrate would refuse without AllowOutOfOrder")
+	}
+}
+
+func TestIgnoreUnknown(t *testing.T) {
+	db, err := sql.Open("pgx", os.Getenv("POSTGRES_URL"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	known := mustMigrator(New(Migrations(&Migration{
+		Version: "unknown-a",
+		Name:    "create unknown_a",
+		Func: func(tx *sql.Tx) error {
+			_, err := tx.Exec("CREATE TABLE unknown_a (id INT PRIMARY KEY)")
+			return err
+		},
+	}), IgnoreUnknown()))
+	if err := known.Migrate(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+
+	withoutIt := mustMigrator(New(Migrations(&Migration{
+		Version: "unknown-b",
+		Name:    "create unknown_b",
+		Func: func(tx *sql.Tx) error {
+			_, err := tx.Exec("CREATE TABLE unknown_b (id INT PRIMARY KEY)")
+			return err
+		},
+	})))
+	if err := withoutIt.Migrate(context.Background(), db); err == nil {
+		t.Fatal("expected an unknown applied version to fail without IgnoreUnknown")
+	}
+
+	withIgnore := mustMigrator(New(
+		Migrations(&Migration{
+			Version: "unknown-c",
+			Name:    "create unknown_c",
+			Func: func(tx *sql.Tx) error {
+				_, err := tx.Exec("CREATE TABLE unknown_c (id INT PRIMARY KEY)")
+				return err
+			},
+		}),
+		IgnoreUnknown(),
+	))
+	if err := withIgnore.Migrate(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPostgresLockerSerializes(t *testing.T) {
+	db, err := sql.Open("pgx", os.Getenv("POSTGRES_URL"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	locker := NewPostgresLocker("locker_test_table")
+
+	conn1, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn1.Close()
+	if err := locker.Lock(context.Background(), conn1); err != nil {
+		t.Fatal(err)
+	}
+
+	conn2, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn2.Close()
+
+	lockCtx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- locker.Lock(lockCtx, conn2)
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("expected the second Lock to block while the first connection holds the lock")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("second Lock call never returned")
+	}
+
+	if err := locker.Unlock(context.Background(), conn1); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,49 @@
+package migrator
+
+import "testing"
+
+func TestPlaceholders(t *testing.T) {
+	if got, want := placeholders("postgres", 3), "$1, $2, $3"; got != want {
+		t.Fatalf("placeholders(postgres, 3) = %q, want %q", got, want)
+	}
+	if got, want := placeholders("mysql", 3), "?, ?, ?"; got != want {
+		t.Fatalf("placeholders(mysql, 3) = %q, want %q", got, want)
+	}
+	if got, want := placeholders("", 1), "?"; got != want {
+		t.Fatalf("placeholders(\"\", 1) = %q, want %q", got, want)
+	}
+}
+
+func TestPlaceholderAt(t *testing.T) {
+	if got, want := placeholderAt("postgres", 2), "$2"; got != want {
+		t.Fatalf("placeholderAt(postgres, 2) = %q, want %q", got, want)
+	}
+	if got, want := placeholderAt("mysql", 2), "?"; got != want {
+		t.Fatalf("placeholderAt(mysql, 2) = %q, want %q", got, want)
+	}
+}
+
+func TestCurrentSchemaSQL(t *testing.T) {
+	if got, want := currentSchemaSQL("postgres"), "current_schema()"; got != want {
+		t.Fatalf("currentSchemaSQL(postgres) = %q, want %q", got, want)
+	}
+	if got, want := currentSchemaSQL("mysql"), "DATABASE()"; got != want {
+		t.Fatalf("currentSchemaSQL(mysql) = %q, want %q", got, want)
+	}
+}
+
+func TestValidateTableName(t *testing.T) {
+	valid := []string{"migrations", "_migrations", "my_schema.migrations", "Migrations2"}
+	for _, name := range valid {
+		if err := validateTableName(name); err != nil {
+			t.Errorf("validateTableName(%q) = %v, want nil", name, err)
+		}
+	}
+
+	invalid := []string{"", "migrations;DROP TABLE users", "migrations'", "1migrations", "a.b.c"}
+	for _, name := range invalid {
+		if err := validateTableName(name); err == nil {
+			t.Errorf("validateTableName(%q) = nil, want error", name)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// MigrationStatus describes a single defined migration joined against the migrations
+// table, as returned by Migrator.Status.
+type MigrationStatus struct {
+	Version   string
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Type      string // "tx" or "no-tx"
+
+	// ChecksumMismatch reports whether this migration's code no longer matches the
+	// checksum recorded when it was applied, the same drift that causes Migrate to
+	// fail with a *ChecksumMismatchError.
+	ChecksumMismatch bool
+}
+
+// Status returns every migration defined in the migrator alongside whether (and when)
+// it has been applied, so operators can inspect the state of a deployment.
+func (m *Migrator) Status(ctx context.Context, db *sql.DB) ([]MigrationStatus, error) {
+	applied, err := appliedRows(ctx, db, m.tableName)
+	if err != nil {
+		return nil, err
+	}
+	appliedByVersion := make(map[string]appliedRow, len(applied))
+	for _, row := range applied {
+		appliedByVersion[row.Version] = row
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		version := versionOf(migration)
+		checksum := checksumOf(migration)
+		status := MigrationStatus{
+			Version: version,
+			Name:    nameOf(migration),
+			Type:    typeOf(migration),
+		}
+		if row, ok := appliedByVersion[version]; ok {
+			status.Applied = true
+			status.AppliedAt = row.AppliedAt
+			status.ChecksumMismatch = checksum != "" && row.Checksum != "" && row.Checksum != checksum
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Direction indicates whether a Plan describes an up (Migrate) or down (Rollback) run.
+type Direction int
+
+const (
+	// Up plans the migrations Migrate would apply.
+	Up Direction = iota
+	// Down plans the migrations Rollback would undo.
+	Down
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Up:
+		return "up"
+	case Down:
+		return "down"
+	default:
+		return "unknown"
+	}
+}
+
+// PlannedStep describes a single migration that Migrate or Rollback would act on.
+type PlannedStep struct {
+	Version   string
+	Name      string
+	Type      string
+	Direction Direction
+}
+
+// Plan previews what Migrate (direction Up) or Rollback (direction Down) would do
+// against db without executing anything, so operators can review a deploy beforehand.
+// For Up it lists every unapplied migration and applies Migrate's declaration-order
+// check; it does not check for unknown applied versions or checksum drift, which make
+// Migrate fail (Status reports drift per migration). For Down it lists every applied
+// migration, most recent first, including versions the migrator doesn't define, which
+// Rollback refuses to undo.
+func (m *Migrator) Plan(ctx context.Context, db *sql.DB, direction Direction) ([]PlannedStep, error) {
+	applied, err := appliedRows(ctx, db, m.tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	switch direction {
+	case Up:
+		appliedVersions := make(map[string]struct{}, len(applied))
+		for _, row := range applied {
+			appliedVersions[row.Version] = struct{}{}
+		}
+
+		lastAppliedIdx := -1
+		for idx, migration := range m.migrations {
+			if _, ok := appliedVersions[versionOf(migration)]; ok {
+				lastAppliedIdx = idx
+			}
+		}
+
+		var steps []PlannedStep
+		for idx, migration := range m.migrations {
+			version := versionOf(migration)
+			if _, ok := appliedVersions[version]; ok {
+				continue
+			}
+			// Mirror migrateUpTo's ordering check: Migrate refuses to apply a migration
+			// declared before one that's already applied unless AllowOutOfOrder is set,
+			// so a plan that didn't check this could preview a step Migrate would
+			// actually reject.
+			if idx < lastAppliedIdx && !m.allowOutOfOrder {
+				return nil, fmt.Errorf("migrator: migration %s (%s) is declared before an already applied migration; enable AllowOutOfOrder to apply it anyway", version, nameOf(migration))
+			}
+			steps = append(steps, PlannedStep{
+				Version:   version,
+				Name:      nameOf(migration),
+				Type:      typeOf(migration),
+				Direction: Up,
+			})
+		}
+		return steps, nil
+	case Down:
+		byVersion := make(map[string]interface{}, len(m.migrations))
+		for _, migration := range m.migrations {
+			byVersion[versionOf(migration)] = migration
+		}
+
+		steps := make([]PlannedStep, 0, len(applied))
+		for i := len(applied) - 1; i >= 0; i-- {
+			row := applied[i]
+			step := PlannedStep{Version: row.Version, Name: row.Name, Direction: Down}
+			if migration, ok := byVersion[row.Version]; ok {
+				step.Type = typeOf(migration)
+			}
+			steps = append(steps, step)
+		}
+		return steps, nil
+	default:
+		return nil, fmt.Errorf("migrator: unknown direction %v", direction)
+	}
+}